@@ -0,0 +1,155 @@
+package launcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+	"time"
+)
+
+// HealthChecker can optionally be implemented by a Goroutine to report
+// its health to the Introspection subsystem (see EnableIntrospection).
+// It is polled while handling /readyz.
+type HealthChecker interface {
+	Healthy(ctx context.Context) error
+}
+
+// Introspection is a built-in Goroutine that exposes /healthz
+// (liveness), /readyz (readiness, aggregated from every other
+// goroutine's HealthChecker), /debug/pprof/* and /metrics. Use
+// Launcher.EnableIntrospection to add one, or construct it directly via
+// NewIntrospection and Launcher.Add it like any other Goroutine.
+type Introspection struct {
+	id         string
+	addr       string
+	launcher   *Launcher
+	drainDelay time.Duration
+
+	srv *http.Server
+
+	mu      sync.RWMutex
+	metrics http.Handler
+}
+
+// NewIntrospection returns a new Introspection goroutine identified by
+// id, listening on addr, reporting on the goroutines registered with l.
+func NewIntrospection(id string, addr string, l *Launcher) *Introspection {
+	return &Introspection{id: id, addr: addr, launcher: l}
+}
+
+// Id returns the goroutine id.
+func (in *Introspection) Id() string {
+	return in.id
+}
+
+// SetMetricsHandler wires an http.Handler, such as promhttp.Handler(),
+// to be served at /metrics. Until it is set, /metrics returns 404.
+func (in *Introspection) SetMetricsHandler(h http.Handler) {
+	in.mu.Lock()
+	in.metrics = h
+	in.mu.Unlock()
+}
+
+// SetDrainDelay sets how long /readyz keeps returning 503 before
+// Shutdown actually closes the server, giving upstream load balancers
+// time to notice and stop routing traffic here. Default is 0.
+func (in *Introspection) SetDrainDelay(d time.Duration) {
+	in.drainDelay = d
+}
+
+// Run starts the introspection HTTP server. It blocks until Shutdown is
+// called.
+func (in *Introspection) Run() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", in.handleHealthz)
+	mux.HandleFunc("/readyz", in.handleReadyz)
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/metrics", in.handleMetrics)
+
+	in.srv = &http.Server{Addr: in.addr, Handler: mux}
+
+	if err := in.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown waits drainDelay, on top of whatever /readyz has already
+// drained since the launcher's context was canceled (see
+// handleReadyz), to give upstream load balancers a last chance to
+// notice before the HTTP server actually closes.
+func (in *Introspection) Shutdown(ctx context.Context) error {
+	if in.drainDelay > 0 {
+		time.Sleep(in.drainDelay)
+	}
+
+	return in.srv.Shutdown(ctx)
+}
+
+// handleHealthz reports liveness: as long as the process can answer,
+// it's alive.
+func (in *Introspection) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// handleReadyz reports readiness: it returns 503 as soon as the
+// launcher's context is canceled - i.e. from the moment a shutdown or
+// restart is requested, regardless of which ShutdownPhase Introspection
+// itself ends up stopped in - and otherwise polls Healthy on every
+// HealthChecker goroutine registered with the launcher.
+func (in *Introspection) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if in.launcher.Context().Err() != nil {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	for _, g := range in.launcher.Goroutines {
+		if g.Id() == in.id {
+			continue
+		}
+		hc, ok := g.(HealthChecker)
+		if !ok {
+			continue
+		}
+		if err := hc.Healthy(ctx); err != nil {
+			http.Error(w, fmt.Sprintf("%s: %v", g.Id(), err), http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// handleMetrics serves the handler registered via SetMetricsHandler, or
+// 404 if none was set.
+func (in *Introspection) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	in.mu.RLock()
+	h := in.metrics
+	in.mu.RUnlock()
+
+	if h == nil {
+		http.NotFound(w, r)
+		return
+	}
+	h.ServeHTTP(w, r)
+}
+
+// EnableIntrospection adds a built-in Introspection goroutine listening
+// on addr to the launcher and returns it, so callers can wire a metrics
+// handler or a drain delay onto it before calling Run.
+func (srv *Launcher) EnableIntrospection(addr string) *Introspection {
+	in := NewIntrospection("introspection", addr, srv)
+	srv.Add(in)
+	return in
+}
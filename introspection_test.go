@@ -0,0 +1,61 @@
+package launcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIntrospectionHandleHealthz(t *testing.T) {
+	in := NewIntrospection("introspection", ":0", New())
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	rec := httptest.NewRecorder()
+	in.handleHealthz(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+}
+
+type fakeHealthChecker struct {
+	id  string
+	err error
+}
+
+func (f *fakeHealthChecker) Id() string                        { return f.id }
+func (f *fakeHealthChecker) Run() error                        { return nil }
+func (f *fakeHealthChecker) Shutdown(ctx context.Context) error { return nil }
+func (f *fakeHealthChecker) Healthy(ctx context.Context) error  { return f.err }
+
+func TestIntrospectionHandleReadyz(t *testing.T) {
+	l := New()
+	healthy := &fakeHealthChecker{id: "healthy"}
+	l.Add(healthy)
+	in := l.EnableIntrospection(":0")
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+	rec := httptest.NewRecorder()
+	in.handleReadyz(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 while every HealthChecker is healthy, got %d", rec.Code)
+	}
+
+	healthy.err = errors.New("db unreachable")
+	rec = httptest.NewRecorder()
+	in.handleReadyz(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 once a HealthChecker fails, got %d", rec.Code)
+	}
+
+	healthy.err = nil
+	l.cancel()
+	rec = httptest.NewRecorder()
+	in.handleReadyz(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 once the launcher's context is canceled, even with a healthy HealthChecker, got %d", rec.Code)
+	}
+}
@@ -3,18 +3,39 @@ package launcher
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net"
 	"os"
+	"os/exec"
 	"os/signal"
+	"sort"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/sprokhorov/logkit"
+	"golang.org/x/sync/errgroup"
 )
 
-// ErrGoroutinesListEmpty returned by Run method if there are no goroutines added.
 var (
+	// ErrGoroutinesListEmpty returned by Run method if there are no goroutines added.
 	ErrGoroutinesListEmpty = errors.New("goroutines list is empty")
+	// ErrDependencyCycle is returned by Run if the dependencies declared
+	// via DependsOn form a cycle.
+	ErrDependencyCycle = errors.New("cycle detected in goroutine dependencies")
+)
+
+const (
+	// listenFdsStart is the first file descriptor number used to hand
+	// inherited listeners to a restarted process, following the
+	// LISTEN_FDS/LISTEN_PID convention popularized by systemd socket
+	// activation.
+	listenFdsStart = 3
+
+	envListenFds = "LISTEN_FDS"
+	envListenPid = "LISTEN_PID"
+	envReadyFd   = "LAUNCHER_READY_FD"
 )
 
 // Goroutine describes required goroutine methods.
@@ -29,33 +50,194 @@ type Goroutine interface {
 	Shutdown(ctx context.Context) error
 }
 
+// ContextRunner can optionally be implemented by a Goroutine that wants
+// to be notified of launcher shutdown/cancellation directly, without
+// relying on OS signals or a blocking Shutdown call. When implemented,
+// RunContext is called instead of Run, with a context that is canceled
+// as soon as the launcher starts shutting down.
+type ContextRunner interface {
+	// RunContext behaves like Goroutine.Run but additionally receives a
+	// context that is canceled once the launcher starts shutting down.
+	RunContext(ctx context.Context) error
+}
+
+// Restartable is implemented by Goroutines that own a net.Listener which
+// must survive a SIGHUP-triggered graceful restart. On SIGHUP the
+// launcher collects every Restartable goroutine's listeners and passes
+// their file descriptors to the freshly exec'd child process so it can
+// keep serving the same sockets without dropping a single connection.
+type Restartable interface {
+	// Listeners returns the net.Listeners that must be inherited by the
+	// restarted process.
+	Listeners() []net.Listener
+}
+
+// Reloader can optionally be implemented by a Goroutine that wants to
+// pick up new configuration on SIGHUP. Reload is called in place, on the
+// running goroutine, when no goroutine is Restartable - it does not
+// terminate or replace the goroutine. When a Restartable goroutine is
+// present, SIGHUP restarts the process instead and Reload is not called
+// (see restartSignalAction).
+type Reloader interface {
+	Reload(ctx context.Context) error
+}
+
+// LogReopener can optionally be implemented by a Goroutine that keeps
+// its own open log file handles and needs to reopen them after external
+// log rotation, in response to SIGUSR1.
+type LogReopener interface {
+	ReopenLogs() error
+}
+
+// SignalAction is the behaviour the launcher invokes when it receives a
+// registered os.Signal. See OnSignal.
+type SignalAction func(srv *Launcher) error
+
+// goroutineOptions holds the per-goroutine settings collected from the
+// Options passed to AddWithOptions.
+type goroutineOptions struct {
+	dependsOn       []string
+	readyProbe      func(ctx context.Context) error
+	startTimeout    time.Duration
+	shutdownPhase   int
+	shutdownTimeout time.Duration
+}
+
+// Option configures how a Goroutine is added to the Launcher via
+// AddWithOptions.
+type Option func(*goroutineOptions)
+
+// DependsOn declares that a goroutine must not be started until every
+// goroutine identified by ids has been started and, if it has a
+// ReadyProbe, reported ready.
+func DependsOn(ids ...string) Option {
+	return func(o *goroutineOptions) {
+		o.dependsOn = append(o.dependsOn, ids...)
+	}
+}
+
+// ReadyProbe registers a readiness probe for a goroutine. After starting
+// the goroutine the launcher polls probe, respecting StartTimeout,
+// before starting anything that depends on it.
+func ReadyProbe(probe func(ctx context.Context) error) Option {
+	return func(o *goroutineOptions) {
+		o.readyProbe = probe
+	}
+}
+
+// StartTimeout bounds how long the launcher waits for a goroutine's
+// ReadyProbe to succeed before Run fails with a timeout error. Default
+// is 30 seconds.
+func StartTimeout(duration time.Duration) Option {
+	return func(o *goroutineOptions) {
+		o.startTimeout = duration
+	}
+}
+
+// ShutdownPhase tags a goroutine with a phase number controlling
+// shutdown order: stopGoroutines waits for phase N to fully finish (or
+// time out) before starting phase N+1. Lower numbers stop first - e.g.
+// 0 for ingress (HTTP/gRPC servers), 1 for workers, 2 for infrastructure
+// (DB/KV stores) that the earlier phases still depend on while draining.
+// Default phase is 0, so goroutines that don't set one all share a
+// single phase, stopped in reverse dependency order (see DependsOn).
+func ShutdownPhase(phase int) Option {
+	return func(o *goroutineOptions) {
+		o.shutdownPhase = phase
+	}
+}
+
+// ShutdownTimeout overrides the launcher's shutdownTimeout for a single
+// goroutine.
+func ShutdownTimeout(duration time.Duration) Option {
+	return func(o *goroutineOptions) {
+		o.shutdownTimeout = duration
+	}
+}
+
 // Launcher manages goroutines from internal stored list.
 type Launcher struct {
 	ch              chan bool
 	waitGroup       *sync.WaitGroup
 	Goroutines      []Goroutine
+	options         map[string]*goroutineOptions
+	signals         map[os.Signal]SignalAction
 	shutdownTimeout time.Duration
-	shuttingDown    bool
+	hammerTime      time.Duration
+	shutdownOnce    sync.Once
+	restarting      bool
 	ctx             context.Context
+	cancel          context.CancelFunc
 	log             logkit.Logger
+
+	mu                 sync.Mutex
+	lastShutdownReport *ShutdownReport
 }
 
-// New returns a new Launcher. It sets shutdownTimeout to 60 seconds by default.
+// New returns a new Launcher. It sets shutdownTimeout to 60 seconds and
+// hammerTime to 10 seconds by default.
+//
+// New also installs the default signal actions: SIGINT/SIGTERM/SIGQUIT
+// shut the launcher down, SIGHUP performs a zero-downtime restart (see
+// Restartable) if any goroutine has a listener to hand over, or
+// otherwise just reloads any Reloader goroutines in place, and SIGUSR1
+// reopens the logs of any LogReopener goroutines. Use OnSignal to
+// replace any of these, or to start handling a signal such as SIGUSR2
+// that has no default behaviour.
 func New() *Launcher {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Launcher{
 		ch:              make(chan bool),
 		waitGroup:       &sync.WaitGroup{},
 		Goroutines:      []Goroutine{},
-		shutdownTimeout: 60,
-		ctx:             context.Background(),
+		options:         map[string]*goroutineOptions{},
+		signals: map[os.Signal]SignalAction{
+			syscall.SIGINT:  shutdownSignalAction,
+			syscall.SIGTERM: shutdownSignalAction,
+			syscall.SIGQUIT: shutdownSignalAction,
+			syscall.SIGHUP:  restartSignalAction,
+			syscall.SIGUSR1: reopenLogsSignalAction,
+		},
+		shutdownTimeout: 60 * time.Second,
+		hammerTime:      10 * time.Second,
+		ctx:             ctx,
+		cancel:          cancel,
 		log:             &logkit.DefaultLogger{},
 	}
 }
 
+// OnSignal registers action as the behaviour to run when the launcher
+// receives sig, replacing any previously registered action for that
+// signal (including the defaults installed by New).
+func (srv *Launcher) OnSignal(sig os.Signal, action func(srv *Launcher) error) {
+	srv.signals[sig] = action
+}
+
+// Context returns the launcher's context. It is canceled once the
+// launcher starts shutting down, which lets Goroutine implementations
+// that opt into ContextRunner react to shutdown/cancellation directly
+// instead of relying on signals.
+func (srv *Launcher) Context() context.Context {
+	return srv.ctx
+}
+
 // Add adds new goroutine to the internal goroutines list.
 func (srv *Launcher) Add(Goroutine Goroutine) {
-	srv.Goroutines = append(srv.Goroutines, Goroutine)
+	srv.AddWithOptions(Goroutine)
+}
+
+// AddWithOptions adds a new goroutine to the internal goroutines list,
+// like Add, but additionally accepts Options such as DependsOn and
+// ReadyProbe to control startup ordering.
+func (srv *Launcher) AddWithOptions(g Goroutine, opts ...Option) {
+	o := &goroutineOptions{startTimeout: 30 * time.Second}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	srv.Goroutines = append(srv.Goroutines, g)
 	srv.waitGroup.Add(1)
+	srv.options[g.Id()] = o
 }
 
 // SetShutdownTimeout change shutdown timeout. Default is 60 seconds.
@@ -63,6 +245,14 @@ func (srv *Launcher) SetShutdownTimeout(duration time.Duration) {
 	srv.shutdownTimeout = duration
 }
 
+// SetHammerTime changes the hammer time, i.e. how long the launcher
+// waits for a restarted child process to become ready before it gives
+// up on the restart and leaves the old goroutines running. Default is
+// 10 seconds.
+func (srv *Launcher) SetHammerTime(duration time.Duration) {
+	srv.hammerTime = duration
+}
+
 func (srv *Launcher) SetLogger(logger logkit.Logger) {
 	srv.log = logger
 }
@@ -70,93 +260,617 @@ func (srv *Launcher) SetLogger(logger logkit.Logger) {
 // Run starts all Goroutines from internal list. It will return ErrGoroutinesListEmpty
 // if goroutines list is empty.
 //
-// Run method listens for syscalls(SIGINT, SIGTERM, SIGQUIT) and calls goroutine.Shutdown
-// method.
+// Run subscribes to every os.Signal registered via OnSignal (including
+// the defaults installed by New) and invokes its SignalAction when the
+// signal arrives.
+//
+// If any Goroutine returns a non-nil error before shutdown was requested,
+// Run cancels the launcher's context, shuts down every other goroutine,
+// and returns an aggregated error (via errors.Join) describing every
+// goroutine that failed.
 func (srv *Launcher) Run() error {
 	// Check setup
 	if len(srv.Goroutines) <= 0 {
 		return ErrGoroutinesListEmpty
 	}
 
-	// Subscribe to the signals
+	// Subscribe to every signal that has a registered action
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh,
-		syscall.SIGINT,
-		syscall.SIGTERM,
-		syscall.SIGQUIT,
-	)
+	sigs := make([]os.Signal, 0, len(srv.signals))
+	for sig := range srv.signals {
+		sigs = append(sigs, sig)
+	}
+	signal.Notify(sigCh, sigs...)
 
 	// Listen for signals in the new goroutine
 	go func() {
-		for {
-			sig := <-sigCh
-			switch sig {
-			default:
-				srv.shuttingDown = true
-				if s, ok := sig.(syscall.Signal); ok {
-					srv.log.Infof("The main process got an %s (%d) signal, stopping goroutines", signalName(s), int(s))
-				}
-				srv.stopGoroutines()
+		for sig := range sigCh {
+			action, ok := srv.signals[sig]
+			if !ok {
+				continue
+			}
+			if s, ok := sig.(syscall.Signal); ok {
+				srv.log.Infof("The main process got an %s (%d) signal", signalName(s), int(s))
+			}
+			if err := action(srv); err != nil {
+				srv.log.Errorf("Signal action for %v failed, %+v", sig, err)
 			}
 		}
 	}()
 
-	// Create a wait group
-	wg := &sync.WaitGroup{}
-	wg.Add(len(srv.Goroutines))
-
-	// Start goroutines
-	srv.startGoroutines(wg)
-	wg.Wait()
-	return nil
+	// Start goroutines and wait for them all to return
+	return srv.startGoroutines()
 }
 
 // Stop terminates the goroutines. This method is needed for manual goroutines stop.
 func (srv *Launcher) Stop() {
-	srv.stopGoroutines()
-}
-
-// startGoroutines loops through the goroutines list in the adding order and
-// starts them all.
-func (srv *Launcher) startGoroutines(wg *sync.WaitGroup) {
-	for i := 0; i <= len(srv.Goroutines)-1; i++ {
-		go func(g Goroutine) {
-			srv.log.Infof("Start goroutine with id %s", g.Id())
-			if err := g.Run(); err != nil {
-				if srv.shuttingDown {
-					srv.log.Errorf("Goroutine with id %s has been terminated, %+v", g.Id(), err)
+	shutdownSignalAction(srv)
+}
+
+// shutdownSignalAction is the default SignalAction for
+// SIGINT/SIGTERM/SIGQUIT: it cancels the launcher's context and stops
+// every goroutine.
+func shutdownSignalAction(srv *Launcher) error {
+	srv.triggerShutdown()
+	return nil
+}
+
+// triggerShutdown cancels the launcher's context and stops every
+// goroutine, exactly once no matter how many callers race to request
+// it. A failing goroutine, a ReadyProbe timeout, a shutdown signal and
+// restart all funnel through this same guarded transition, so
+// stopGoroutines - and hence every Goroutine.Shutdown - never runs more
+// than once per lifecycle even if several goroutines fail concurrently.
+func (srv *Launcher) triggerShutdown() {
+	srv.shutdownOnce.Do(func() {
+		srv.cancel()
+		srv.stopGoroutines()
+	})
+}
+
+// restartSignalAction is the default SignalAction for SIGHUP. If any
+// goroutine implements Restartable, a listener needs to be handed over,
+// so it performs a zero-downtime restart (see restart), which picks up
+// new configuration simply by virtue of being a fresh process. Otherwise
+// there is nothing to hand over, so it just gives every Reloader
+// goroutine a chance to reload its configuration in place - config
+// hot-reload without forking the launcher at all.
+func restartSignalAction(srv *Launcher) error {
+	for _, g := range srv.Goroutines {
+		if _, ok := g.(Restartable); ok {
+			srv.restart()
+			return nil
+		}
+	}
+	srv.reload()
+	return nil
+}
+
+// reopenLogsSignalAction is the default SignalAction for SIGUSR1: it
+// calls ReopenLogs on every goroutine that implements LogReopener.
+func reopenLogsSignalAction(srv *Launcher) error {
+	for _, g := range srv.Goroutines {
+		lr, ok := g.(LogReopener)
+		if !ok {
+			continue
+		}
+		if err := lr.ReopenLogs(); err != nil {
+			srv.log.Errorf("Failed to reopen logs for goroutine %s, %+v", g.Id(), err)
+		}
+	}
+	return nil
+}
+
+// reload calls Reload on every goroutine that implements Reloader,
+// giving it a chance to pick up new configuration in place. It is the
+// default SIGHUP behaviour for a launcher with no Restartable goroutines
+// (see restartSignalAction); restart itself never calls reload, since
+// the restarted process already picks up new configuration on its own.
+func (srv *Launcher) reload() {
+	ctx, cancel := context.WithTimeout(srv.ctx, srv.shutdownTimeout)
+	defer cancel()
+
+	for _, g := range srv.Goroutines {
+		r, ok := g.(Reloader)
+		if !ok {
+			continue
+		}
+		if err := r.Reload(ctx); err != nil {
+			srv.log.Errorf("Failed to reload goroutine %s, %+v", g.Id(), err)
+		}
+	}
+}
+
+// startGoroutines starts the goroutines list via errgroup in topological
+// batches computed from their declared dependencies (see DependsOn):
+// goroutines in batch N only start once every goroutine in batches
+// 0..N-1 has started and, if it has a ReadyProbe, reported ready. If a
+// Goroutine implements ContextRunner, RunContext is called instead of
+// Run so it can react to the launcher's context being canceled. The
+// first goroutine to fail outside of a requested shutdown, or a
+// ReadyProbe timeout, triggers a shutdown of every other goroutine;
+// startGoroutines returns all errors joined together. A dependency
+// cycle or an unknown dependency is returned before any goroutine is
+// started.
+//
+// Once every batch has started and reported ready, signalReady is
+// called before blocking on the goroutines' completion - this is what
+// lets a restarted child process (see restart) tell its parent it's
+// ready to take over traffic while it's still actively serving it.
+func (srv *Launcher) startGoroutines() error {
+	batches, err := srv.topoBatches()
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	appendErr := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+
+	g, ctx := errgroup.WithContext(srv.ctx)
+
+	for _, batch := range batches {
+		for i := range batch {
+			gr := batch[i]
+			g.Go(func() error {
+				srv.log.Infof("Start goroutine with id %s", gr.Id())
+
+				var err error
+				if cr, ok := gr.(ContextRunner); ok {
+					err = cr.RunContext(ctx)
 				} else {
-					srv.log.Fatalf("Failed to start goroutine %s, %v", g.Id(), err)
+					err = gr.Run()
+				}
+
+				if err == nil {
+					srv.log.Infof("Goroutine with id %s has been terminated without an error", gr.Id())
+					return nil
+				}
+
+				if srv.ctx.Err() != nil {
+					srv.log.Errorf("Goroutine with id %s has been terminated, %+v", gr.Id(), err)
+				} else {
+					srv.log.Errorf("Goroutine %s failed, stopping the remaining goroutines, %v", gr.Id(), err)
+					srv.triggerShutdown()
+				}
+
+				wrapped := fmt.Errorf("goroutine %s: %w", gr.Id(), err)
+				appendErr(wrapped)
+				return wrapped
+			})
+		}
+
+		if err := srv.awaitReady(ctx, batch); err != nil {
+			srv.log.Errorf("%+v", err)
+			srv.triggerShutdown()
+			appendErr(err)
+			g.Wait()
+			return errors.Join(errs...)
+		}
+	}
+
+	// Every batch has been started and reported ready: let a parent
+	// process that triggered a SIGHUP restart know it's safe to take
+	// over traffic, then block until every goroutine has returned.
+	srv.signalReady()
+
+	g.Wait()
+
+	// A shutdown requested from elsewhere (a signal, Stop, a restart) may
+	// still be stopping goroutines whose Run has already returned; wait
+	// for it to finish so the ShutdownReport is in place when Run returns.
+	if srv.ctx.Err() != nil {
+		srv.triggerShutdown()
+	}
+	return errors.Join(errs...)
+}
+
+// awaitReady blocks until every goroutine in batch that declared a
+// ReadyProbe reports ready, or returns a timeout error bounded by its
+// StartTimeout.
+func (srv *Launcher) awaitReady(ctx context.Context, batch []Goroutine) error {
+	for _, gr := range batch {
+		opts := srv.options[gr.Id()]
+		if opts == nil || opts.readyProbe == nil {
+			continue
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, opts.startTimeout)
+		err := pollReady(probeCtx, opts.readyProbe)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("goroutine %s did not become ready: %w", gr.Id(), err)
+		}
+		srv.log.Infof("Goroutine with id %s is ready", gr.Id())
+	}
+	return nil
+}
+
+// pollReady calls probe until it returns nil or ctx is done.
+func pollReady(ctx context.Context, probe func(ctx context.Context) error) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if err := probe(ctx); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// topoBatches groups the added goroutines into topological batches based
+// on their declared dependencies: every goroutine in batch N depends
+// only on goroutines in batches 0..N-1. It returns ErrDependencyCycle if
+// the dependencies form a cycle, or an error if a goroutine depends on
+// an id that was never added.
+func (srv *Launcher) topoBatches() ([][]Goroutine, error) {
+	dependsOn := make(map[string][]string, len(srv.Goroutines))
+	known := make(map[string]bool, len(srv.Goroutines))
+	for _, gr := range srv.Goroutines {
+		known[gr.Id()] = true
+	}
+	for _, gr := range srv.Goroutines {
+		opts := srv.options[gr.Id()]
+		if opts == nil {
+			continue
+		}
+		for _, dep := range opts.dependsOn {
+			if !known[dep] {
+				return nil, fmt.Errorf("goroutine %s depends on unknown goroutine %s", gr.Id(), dep)
+			}
+		}
+		dependsOn[gr.Id()] = opts.dependsOn
+	}
+
+	var batches [][]Goroutine
+	done := make(map[string]bool, len(srv.Goroutines))
+	for len(done) < len(srv.Goroutines) {
+		var batch []Goroutine
+		for _, gr := range srv.Goroutines {
+			if done[gr.Id()] {
+				continue
+			}
+			ready := true
+			for _, dep := range dependsOn[gr.Id()] {
+				if !done[dep] {
+					ready = false
+					break
 				}
-			} else {
-				srv.log.Infof("Goroutine with id %s has been terminated without an error", g.Id())
 			}
-			wg.Done()
-		}(srv.Goroutines[i])
+			if ready {
+				batch = append(batch, gr)
+			}
+		}
+		if len(batch) == 0 {
+			return nil, ErrDependencyCycle
+		}
+		for _, gr := range batch {
+			done[gr.Id()] = true
+		}
+		batches = append(batches, batch)
 	}
+	return batches, nil
 }
 
-// stopGoroutines loops through the goroutines list and stops them all.
-// If goroutine didn't stop during the srv.shutdownTimeout it will be
-// killed by the system.
-//
-// stopGoroutines loops through the goroutines list in reverse order in case
-// if the newer goroutines depend on the early created.
+// ShutdownReport summarizes the outcome of the most recent call to
+// stopGoroutines, one PhaseReport per ShutdownPhase that was processed,
+// in stop order. Fetch it after Run returns via Launcher.ShutdownReport.
+type ShutdownReport struct {
+	Phases []PhaseReport
+}
+
+// PhaseReport summarizes the outcome of stopping every goroutine tagged
+// with a given ShutdownPhase.
+type PhaseReport struct {
+	Phase    int
+	Clean    []string
+	Failed   []string
+	TimedOut []string
+}
+
+// ShutdownReport returns the outcome of the most recent shutdown, or nil
+// if the launcher hasn't stopped yet.
+func (srv *Launcher) ShutdownReport() *ShutdownReport {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.lastShutdownReport
+}
+
+// phaseGoroutines is a ShutdownPhase together with the goroutines tagged
+// with it, grouped into waves that must be stopped in order: stopGoroutines
+// waits for wave N to fully finish (or time out) before starting wave
+// N+1, so a goroutine is only stopped once every goroutine in the same
+// phase that depends on it has already stopped. Goroutines within a
+// wave have no dependency relationship between them and are stopped
+// concurrently.
+type phaseGoroutines struct {
+	phase int
+	waves [][]Goroutine
+}
+
+// shutdownPhases groups goroutines by their declared ShutdownPhase,
+// ascending so the lowest phase (e.g. ingress) stops first. Within a
+// phase, goroutines are further split into waves derived from the
+// reverse of their startup topoBatches, so dependents genuinely stop
+// before the goroutines they depend on rather than merely racing them.
+// Without any ShutdownPhase declared, every goroutine defaults to phase
+// 0, so this produces a single phase whose waves are just the reversed
+// startup batches.
+func (srv *Launcher) shutdownPhases() []phaseGoroutines {
+	batches, err := srv.topoBatches()
+	if err != nil {
+		srv.log.Errorf("Failed to compute shutdown order, falling back to insertion order, %+v", err)
+		batches = [][]Goroutine{srv.Goroutines}
+	}
+
+	phaseOf := func(g Goroutine) int {
+		if opts := srv.options[g.Id()]; opts != nil {
+			return opts.shutdownPhase
+		}
+		return 0
+	}
+
+	wavesByPhase := map[int][][]Goroutine{}
+	seenPhase := map[int]bool{}
+	var phases []int
+	for i := len(batches) - 1; i >= 0; i-- {
+		waveByPhase := map[int][]Goroutine{}
+		for _, g := range batches[i] {
+			phase := phaseOf(g)
+			waveByPhase[phase] = append(waveByPhase[phase], g)
+			if !seenPhase[phase] {
+				seenPhase[phase] = true
+				phases = append(phases, phase)
+			}
+		}
+		for phase, wave := range waveByPhase {
+			wavesByPhase[phase] = append(wavesByPhase[phase], wave)
+		}
+	}
+	sort.Ints(phases)
+
+	result := make([]phaseGoroutines, 0, len(phases))
+	for _, phase := range phases {
+		result = append(result, phaseGoroutines{phase: phase, waves: wavesByPhase[phase]})
+	}
+	return result
+}
+
+// shutdownTimeoutFor returns g's ShutdownTimeout override if one was set
+// via AddWithOptions, or the launcher's shutdownTimeout otherwise.
+func (srv *Launcher) shutdownTimeoutFor(g Goroutine) time.Duration {
+	if opts := srv.options[g.Id()]; opts != nil && opts.shutdownTimeout > 0 {
+		return opts.shutdownTimeout
+	}
+	return srv.shutdownTimeout
+}
+
+// stopGoroutines stops every goroutine, phase by phase in ascending
+// ShutdownPhase order, and within a phase wave by wave (see
+// shutdownPhases): it blocks until every goroutine in a wave has
+// returned from Shutdown or hit its timeout (srv.shutdownTimeout, or its
+// ShutdownTimeout override) before moving on to the next wave, and on to
+// the next phase once every wave in it is done. The outcome is recorded
+// as a ShutdownReport, available afterwards via Launcher.ShutdownReport.
 func (srv *Launcher) stopGoroutines() {
-	ctx, cancel := context.WithTimeout(
-		srv.ctx,
-		srv.shutdownTimeout*time.Second,
-	)
-	defer cancel()
+	report := &ShutdownReport{}
+
+	for _, phase := range srv.shutdownPhases() {
+		pr := PhaseReport{Phase: phase.phase}
+
+		for _, wave := range phase.waves {
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			wg.Add(len(wave))
+
+			for _, g := range wave {
+				g := g
+				go func() {
+					defer wg.Done()
 
-	for i := len(srv.Goroutines) - 1; i >= 0; i-- {
-		go func(g Goroutine) {
-			srv.log.Infof("Trying to stop goroutine with id %s", g.Id())
-			if err := g.Shutdown(ctx); err != nil {
-				srv.log.Errorf("Failed to stop goroutine with id %s, %+v", g.Id(), err)
+					ctx, cancel := context.WithTimeout(context.Background(), srv.shutdownTimeoutFor(g))
+					defer cancel()
+
+					srv.log.Infof("Trying to stop goroutine with id %s", g.Id())
+					err := g.Shutdown(ctx)
+
+					mu.Lock()
+					defer mu.Unlock()
+					switch {
+					case err == nil:
+						pr.Clean = append(pr.Clean, g.Id())
+					case ctx.Err() != nil:
+						srv.log.Errorf("Goroutine with id %s timed out while stopping, %+v", g.Id(), err)
+						pr.TimedOut = append(pr.TimedOut, g.Id())
+					default:
+						srv.log.Errorf("Failed to stop goroutine with id %s, %+v", g.Id(), err)
+						pr.Failed = append(pr.Failed, g.Id())
+					}
+				}()
+			}
+
+			wg.Wait()
+		}
+
+		report.Phases = append(report.Phases, pr)
+	}
+
+	srv.mu.Lock()
+	srv.lastShutdownReport = report
+	srv.mu.Unlock()
+}
+
+// restart performs a zero-downtime restart of the process: it forks/execs
+// the current binary, handing every Restartable goroutine's listeners
+// over via ExtraFiles and the LISTEN_FDS/LISTEN_PID env convention,
+// waits for the child to signal readiness over a pipe, and only then
+// shuts down the old goroutines. If the child doesn't become ready
+// within hammerTime the restart is aborted, the child is killed so it
+// doesn't linger holding the handed-over listener fds, and the old
+// goroutines keep running.
+func (srv *Launcher) restart() {
+	if srv.restarting {
+		srv.log.Infof("Restart already in progress, ignoring SIGHUP")
+		return
+	}
+	srv.restarting = true
+	defer func() { srv.restarting = false }()
+
+	var listenerFiles []*os.File
+	for _, g := range srv.Goroutines {
+		r, ok := g.(Restartable)
+		if !ok {
+			continue
+		}
+		for _, ln := range r.Listeners() {
+			f, err := listenerFile(ln)
+			if err != nil {
+				srv.log.Errorf("Failed to extract fd from a listener of goroutine %s, %+v", g.Id(), err)
+				continue
 			}
-		}(srv.Goroutines[i])
+			listenerFiles = append(listenerFiles, f)
+		}
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		srv.log.Errorf("Failed to create a restart readiness pipe, %+v", err)
+		return
 	}
+	defer readyR.Close()
+
+	execPath, err := os.Executable()
+	if err != nil {
+		srv.log.Errorf("Failed to resolve the current executable, %+v", err)
+		readyW.Close()
+		return
+	}
+
+	cmd := exec.Command(execPath, os.Args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	cmd.ExtraFiles = append(listenerFiles, readyW)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%d", envListenFds, len(listenerFiles)),
+		fmt.Sprintf("%s=0", envListenPid),
+		fmt.Sprintf("%s=%d", envReadyFd, listenFdsStart+len(listenerFiles)),
+	)
+
+	if err := cmd.Start(); err != nil {
+		srv.log.Errorf("Failed to start the restarted process, %+v", err)
+		readyW.Close()
+		return
+	}
+	readyW.Close()
+
+	ready := make(chan struct{})
+	go func() {
+		buf := make([]byte, 1)
+		readyR.Read(buf)
+		close(ready)
+	}()
+
+	select {
+	case <-ready:
+		srv.log.Infof("Child process %d signaled readiness, stopping the old goroutines", cmd.Process.Pid)
+	case <-time.After(srv.hammerTime):
+		srv.log.Errorf("Timed out waiting for child process %d to become ready, killing it and aborting the restart", cmd.Process.Pid)
+		if err := cmd.Process.Kill(); err != nil {
+			srv.log.Errorf("Failed to kill child process %d, %+v", cmd.Process.Pid, err)
+		}
+		go cmd.Wait()
+		return
+	}
+
+	srv.triggerShutdown()
+}
+
+// signalReady notifies a parent process that started us as part of a
+// restart that every goroutine has been started and is ready to take
+// over traffic. It is a no-op when the process wasn't exec'd as part of
+// a restart.
+func (srv *Launcher) signalReady() {
+	fdStr := os.Getenv(envReadyFd)
+	if fdStr == "" {
+		return
+	}
+	fd, err := strconv.Atoi(fdStr)
+	if err != nil {
+		srv.log.Errorf("Invalid %s value %q, %+v", envReadyFd, fdStr, err)
+		return
+	}
+	file := os.NewFile(uintptr(fd), "restart-ready")
+	defer file.Close()
+	if _, err := file.Write([]byte{1}); err != nil {
+		srv.log.Errorf("Failed to signal restart readiness, %+v", err)
+	}
+}
+
+// listenerFile extracts the underlying *os.File from a net.Listener so it
+// can be passed to a restarted process via exec.Cmd.ExtraFiles.
+func listenerFile(ln net.Listener) (*os.File, error) {
+	type filer interface {
+		File() (*os.File, error)
+	}
+	f, ok := ln.(filer)
+	if !ok {
+		return nil, fmt.Errorf("listener %T does not support File()", ln)
+	}
+	return f.File()
+}
+
+// Listen returns a net.Listener for the given network and address. If the
+// process was exec'd as part of a SIGHUP graceful restart it reuses the
+// next inherited file descriptor instead of binding a new socket, so
+// Goroutine implementations can use it in place of net.Listen and keep
+// working unchanged across restarts.
+func Listen(network, address string) (net.Listener, error) {
+	if fd, ok := nextInheritedFd(); ok {
+		file := os.NewFile(fd, fmt.Sprintf("listener-fd%d", fd))
+		defer file.Close()
+		return net.FileListener(file)
+	}
+	return net.Listen(network, address)
+}
+
+var (
+	inheritMu   sync.Mutex
+	inheritNext int
+)
+
+// nextInheritedFd returns the next inherited listener file descriptor,
+// following the LISTEN_FDS/LISTEN_PID env convention popularized by
+// systemd socket activation. LISTEN_PID can't be known by the parent
+// before it execs the child, so restart() sets it to "0" meaning "any
+// process may claim these descriptors".
+func nextInheritedFd() (uintptr, bool) {
+	inheritMu.Lock()
+	defer inheritMu.Unlock()
+
+	if pid := os.Getenv(envListenPid); pid != "" && pid != "0" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(os.Getenv(envListenFds))
+	if err != nil || n <= 0 || inheritNext >= n {
+		return 0, false
+	}
+
+	fd := uintptr(listenFdsStart + inheritNext)
+	inheritNext++
+	return fd, true
 }
 
 // signalName returns a name of the signal
@@ -2,8 +2,11 @@ package launcher
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -44,6 +47,42 @@ func TestRun(t *testing.T) {
 	l.Run()
 }
 
+type failingGoroutine struct {
+	id string
+}
+
+func (fg *failingGoroutine) Id() string                        { return fg.id }
+func (fg *failingGoroutine) Run() error                        { return errors.New("boom") }
+func (fg *failingGoroutine) Shutdown(ctx context.Context) error { return nil }
+
+func TestRunAggregatesFailureAndStopsOthers(t *testing.T) {
+	failing := &failingGoroutine{id: "failing"}
+	other := newCS("other")
+
+	l := New()
+	l.Add(failing)
+	l.Add(other)
+
+	err := l.Run()
+	if err == nil {
+		t.Fatal("expected Run to return an error when a goroutine fails outside of shutdown")
+	}
+	if !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected the returned error to wrap the failing goroutine's error, got %+v", err)
+	}
+}
+
+func TestRunHandlesConcurrentFailuresWithoutPanicking(t *testing.T) {
+	l := New()
+	for i := 0; i < 4; i++ {
+		l.Add(&failingGoroutine{id: fmt.Sprintf("failing%d", i)})
+	}
+
+	if err := l.Run(); err == nil {
+		t.Fatal("expected Run to return a joined error when every goroutine fails")
+	}
+}
+
 type customGoroutine struct {
 	id       string
 	shutdown chan struct{}
@@ -69,11 +108,49 @@ func newCS(id string) Goroutine {
 	return &customGoroutine{id: id, shutdown: make(chan struct{})}
 }
 
+type noopGoroutine struct {
+	id string
+}
+
+func (n *noopGoroutine) Id() string                        { return n.id }
+func (n *noopGoroutine) Run() error                         { return nil }
+func (n *noopGoroutine) Shutdown(ctx context.Context) error { return nil }
+
+func TestTopoBatchesOrdering(t *testing.T) {
+	l := New()
+	l.AddWithOptions(&noopGoroutine{id: "db"})
+	l.AddWithOptions(&noopGoroutine{id: "cache"}, DependsOn("db"))
+	l.AddWithOptions(&noopGoroutine{id: "api"}, DependsOn("db", "cache"))
+
+	batches, err := l.topoBatches()
+	if err != nil {
+		t.Fatalf("topoBatches returned an error, %+v", err)
+	}
+	if len(batches) != 3 {
+		t.Fatalf("expected 3 batches, got %d: %+v", len(batches), batches)
+	}
+	for i, id := range []string{"db", "cache", "api"} {
+		if len(batches[i]) != 1 || batches[i][0].Id() != id {
+			t.Fatalf("expected batch %d to contain only %s, got %+v", i, id, batches[i])
+		}
+	}
+}
+
+func TestTopoBatchesCycle(t *testing.T) {
+	l := New()
+	l.AddWithOptions(&noopGoroutine{id: "a"}, DependsOn("b"))
+	l.AddWithOptions(&noopGoroutine{id: "b"}, DependsOn("a"))
+
+	if _, err := l.topoBatches(); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("expected ErrDependencyCycle, got %+v", err)
+	}
+}
+
 func TestShutdownTimeout(t *testing.T) {
 	cs := newCS("custom1")
 
 	l := New()
-	l.SetShutdownTimeout(1)
+	l.SetShutdownTimeout(time.Second)
 	l.Add(cs)
 
 	go func() {
@@ -84,3 +161,100 @@ func TestShutdownTimeout(t *testing.T) {
 
 	l.Run()
 }
+
+type instantGoroutine struct {
+	id string
+}
+
+func (ig *instantGoroutine) Id() string                        { return ig.id }
+func (ig *instantGoroutine) Run() error                        { return nil }
+func (ig *instantGoroutine) Shutdown(ctx context.Context) error { return nil }
+
+func TestShutdownReportPhasesAndTimeout(t *testing.T) {
+	fast := &instantGoroutine{id: "fast"}
+	slow := newCS("slow")
+
+	l := New()
+	l.AddWithOptions(fast, ShutdownPhase(0))
+	l.AddWithOptions(slow, ShutdownPhase(1), ShutdownTimeout(50*time.Millisecond))
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		l.Stop()
+	}()
+
+	l.Run()
+
+	report := l.ShutdownReport()
+	if report == nil || len(report.Phases) != 2 {
+		t.Fatalf("expected a 2-phase ShutdownReport, got %+v", report)
+	}
+	if report.Phases[0].Phase != 0 || len(report.Phases[0].Clean) != 1 || report.Phases[0].Clean[0] != "fast" {
+		t.Fatalf("expected phase 0 to cleanly stop fast, got %+v", report.Phases[0])
+	}
+	if report.Phases[1].Phase != 1 || len(report.Phases[1].TimedOut) != 1 || report.Phases[1].TimedOut[0] != "slow" {
+		t.Fatalf("expected phase 1 to time out stopping slow, got %+v", report.Phases[1])
+	}
+}
+
+type reloadGoroutine struct {
+	id       string
+	reloaded bool
+}
+
+func (rg *reloadGoroutine) Id() string                        { return rg.id }
+func (rg *reloadGoroutine) Run() error                        { return nil }
+func (rg *reloadGoroutine) Shutdown(ctx context.Context) error { return nil }
+func (rg *reloadGoroutine) Reload(ctx context.Context) error   { rg.reloaded = true; return nil }
+
+func TestRestartSignalActionReloadsInPlaceWithoutForking(t *testing.T) {
+	l := New()
+	rg := &reloadGoroutine{id: "reloadable"}
+	l.Add(rg)
+
+	if err := restartSignalAction(l); err != nil {
+		t.Fatalf("restartSignalAction returned an error, %+v", err)
+	}
+	if !rg.reloaded {
+		t.Fatal("expected restartSignalAction to reload in place when no goroutine is Restartable")
+	}
+}
+
+type logReopenGoroutine struct {
+	id       string
+	reopened bool
+}
+
+func (lg *logReopenGoroutine) Id() string                        { return lg.id }
+func (lg *logReopenGoroutine) Run() error                        { return nil }
+func (lg *logReopenGoroutine) Shutdown(ctx context.Context) error { return nil }
+func (lg *logReopenGoroutine) ReopenLogs() error                  { lg.reopened = true; return nil }
+
+func TestReopenLogsSignalActionCallsLogReopener(t *testing.T) {
+	l := New()
+	lg := &logReopenGoroutine{id: "logger"}
+	l.Add(lg)
+
+	if err := reopenLogsSignalAction(l); err != nil {
+		t.Fatalf("reopenLogsSignalAction returned an error, %+v", err)
+	}
+	if !lg.reopened {
+		t.Fatal("expected reopenLogsSignalAction to call ReopenLogs on a LogReopener goroutine")
+	}
+}
+
+func TestOnSignalOverridesDefault(t *testing.T) {
+	l := New()
+	var called bool
+	l.OnSignal(syscall.SIGHUP, func(l *Launcher) error {
+		called = true
+		return nil
+	})
+
+	if err := l.signals[syscall.SIGHUP](l); err != nil {
+		t.Fatalf("overridden SIGHUP action returned an error, %+v", err)
+	}
+	if !called {
+		t.Fatal("expected OnSignal to replace the default SIGHUP action")
+	}
+}